@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec marshals and unmarshals request/response bodies for a single
+// content type. Context.Render picks one by inspecting Accept; Context.Bind
+// picks one by inspecting Content-Type.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	ContentType() string
+}
+
+// jsonCodec is the only codec built into core, so Server works with zero
+// external dependencies. Additional codecs (YAML, protobuf, msgpack, ...)
+// live in server/codecs/* and register themselves via CodecFactories.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) ContentType() string                        { return "application/json" }
+
+// CodecFactories lets optional codec packages (server/codecs/yaml,
+// server/codecs/protobuf, server/codecs/msgpack, ...) register themselves
+// with every Server created after they're imported, the same way
+// server/observability plugs into Config.Observability.
+var CodecFactories []func() Codec
+
+// CodecRegistry holds the codecs a Server negotiates between, keyed by
+// MIME type.
+type CodecRegistry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+}
+
+func newCodecRegistry() *CodecRegistry {
+	r := &CodecRegistry{codecs: make(map[string]Codec)}
+	r.Register(jsonCodec{})
+	for _, factory := range CodecFactories {
+		r.Register(factory())
+	}
+	return r
+}
+
+// Register adds or replaces the codec for its ContentType().
+func (r *CodecRegistry) Register(codec Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codecs[codec.ContentType()] = codec
+}
+
+// Get returns the codec registered for contentType, ignoring any
+// parameters (e.g. "application/json; charset=utf-8" matches "application/json").
+func (r *CodecRegistry) Get(contentType string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codecs[stripParams(contentType)]
+	return c, ok
+}
+
+// negotiate picks the best codec for an Accept header, honoring quality
+// values, and falls back to JSON when nothing registered matches.
+func (r *CodecRegistry) negotiate(accept string) Codec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, mime := range rankAccept(accept) {
+		if mime == "*/*" {
+			break
+		}
+		if c, ok := r.codecs[mime]; ok {
+			return c
+		}
+	}
+	if c, ok := r.codecs["application/json"]; ok {
+		return c
+	}
+	return jsonCodec{}
+}
+
+// rankAccept parses an Accept header into MIME types ordered by descending
+// quality value (default q=1).
+func rankAccept(accept string) []string {
+	if accept == "" {
+		return nil
+	}
+
+	type candidate struct {
+		mime string
+		q    float64
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mime := part
+		q := 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			mime = strings.TrimSpace(part[:i])
+			for _, param := range strings.Split(part[i+1:], ";") {
+				param = strings.TrimSpace(param)
+				if v, ok := strings.CutPrefix(param, "q="); ok {
+					if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+		}
+		candidates = append(candidates, candidate{mime, q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	mimes := make([]string, len(candidates))
+	for i, c := range candidates {
+		mimes[i] = c.mime
+	}
+	return mimes
+}
+
+func stripParams(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// RegisterCodec adds codec to the server's registry, or replaces whatever
+// was registered for the same content type.
+func (s *Server) RegisterCodec(codec Codec) {
+	if s.codecs == nil {
+		s.codecs = newCodecRegistry()
+	}
+	s.codecs.Register(codec)
+}
+
+// codecRegistry returns the Context's owning Server's registry, or a
+// package-wide default (JSON plus whatever codec packages are imported)
+// for contexts built outside of a Server, e.g. in tests.
+func (c *Context) codecRegistry() *CodecRegistry {
+	if c.server != nil && c.server.codecs != nil {
+		return c.server.codecs
+	}
+	defaultCodecRegistryOnce.Do(func() { defaultCodecRegistry = newCodecRegistry() })
+	return defaultCodecRegistry
+}
+
+// defaultCodecRegistry is built on first use rather than at package init:
+// codec packages import this one, so their init() functions append to
+// CodecFactories only after this package's variables are initialized.
+var (
+	defaultCodecRegistryOnce sync.Once
+	defaultCodecRegistry     *CodecRegistry
+)
+
+// Render sends data using the codec chosen by negotiating the request's
+// Accept header (respecting quality values), defaulting to JSON.
+func (c *Context) Render(data interface{}) error {
+	codec := c.codecRegistry().negotiate(c.Header("Accept"))
+	body, err := codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+	c.Response.Header().Set("Content-Type", codec.ContentType())
+	_, err = c.Response.Write(body)
+	return err
+}
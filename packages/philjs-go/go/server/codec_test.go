@@ -0,0 +1,78 @@
+package server
+
+import "testing"
+
+func TestRankAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"single", "application/json", []string{"application/json"}},
+		{
+			"q-values order candidates",
+			"text/html;q=0.5, application/json;q=0.9, */*;q=0.1",
+			[]string{"application/json", "text/html", "*/*"},
+		},
+		{
+			"default q is 1",
+			"application/xml, application/json;q=0.8",
+			[]string{"application/xml", "application/json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rankAccept(tt.accept)
+			if len(got) != len(tt.want) {
+				t.Fatalf("rankAccept(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("rankAccept(%q)[%d] = %q, want %q", tt.accept, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCodecRegistryNegotiate(t *testing.T) {
+	r := newCodecRegistry()
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"exact json", "application/json", "application/json"},
+		{"q-value picks highest registered", "text/html;q=0.9, application/json;q=0.5", "application/json"},
+		{"unregistered type falls back to json", "application/x-unknown", "application/json"},
+		{"empty accept falls back to json", "", "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := r.negotiate(tt.accept)
+			if codec.ContentType() != tt.want {
+				t.Errorf("negotiate(%q).ContentType() = %q, want %q", tt.accept, codec.ContentType(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCodecRegistryGetStripsParams(t *testing.T) {
+	r := newCodecRegistry()
+
+	codec, ok := r.Get("application/json; charset=utf-8")
+	if !ok {
+		t.Fatal("Get: want a codec registered for application/json")
+	}
+	if codec.ContentType() != "application/json" {
+		t.Errorf("ContentType() = %q, want application/json", codec.ContentType())
+	}
+
+	if _, ok := r.Get("application/x-unregistered"); ok {
+		t.Error("Get: want no codec registered for application/x-unregistered")
+	}
+}
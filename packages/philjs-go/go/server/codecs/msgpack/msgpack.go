@@ -0,0 +1,21 @@
+// Package msgpack provides an application/msgpack Codec for
+// server.Context.Render and Context.Bind. Importing this package (even as a
+// blank import) registers it with every Server created afterwards.
+package msgpack
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/PhilipJohnBasile/philjs/packages/philjs-go/go/server"
+)
+
+func init() {
+	server.CodecFactories = append(server.CodecFactories, func() server.Codec { return Codec{} })
+}
+
+// Codec marshals and unmarshals application/msgpack bodies.
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+func (Codec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+func (Codec) ContentType() string                        { return "application/msgpack" }
@@ -0,0 +1,40 @@
+// Package protobuf provides an application/x-protobuf Codec for
+// server.Context.Render and Context.Bind. Importing this package (even as a
+// blank import) registers it with every Server created afterwards.
+//
+// Unlike the JSON/YAML/msgpack codecs, Marshal/Unmarshal require v to
+// implement proto.Message; anything else returns an error.
+package protobuf
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PhilipJohnBasile/philjs/packages/philjs-go/go/server"
+)
+
+func init() {
+	server.CodecFactories = append(server.CodecFactories, func() server.Codec { return Codec{} })
+}
+
+// Codec marshals and unmarshals application/x-protobuf bodies.
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+func (Codec) ContentType() string { return "application/x-protobuf" }
@@ -0,0 +1,21 @@
+// Package yaml provides an application/yaml Codec for server.Context.Render
+// and Context.Bind. Importing this package (even as a blank import)
+// registers it with every Server created afterwards.
+package yaml
+
+import (
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/PhilipJohnBasile/philjs/packages/philjs-go/go/server"
+)
+
+func init() {
+	server.CodecFactories = append(server.CodecFactories, func() server.Codec { return Codec{} })
+}
+
+// Codec marshals and unmarshals application/yaml bodies.
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error)      { return yamlv3.Marshal(v) }
+func (Codec) Unmarshal(data []byte, v interface{}) error { return yamlv3.Unmarshal(data, v) }
+func (Codec) ContentType() string                        { return "application/yaml" }
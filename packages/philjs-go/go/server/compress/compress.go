@@ -0,0 +1,82 @@
+// Package compress provides response-compression middleware (gzip, brotli,
+// zstd) for server.Server, negotiated per request from Accept-Encoding.
+// Importing this package (even as a blank import) registers it with
+// Config.Compress.
+package compress
+
+import (
+	"github.com/PhilipJohnBasile/philjs/packages/philjs-go/go/server"
+)
+
+func init() {
+	server.CompressFactory = func() server.Middleware { return New() }
+}
+
+// Option configures New.
+type Option func(*config)
+
+type config struct {
+	minSize int
+	allowed []string
+}
+
+// defaultAllowedTypes are the Content-Type prefixes/values compression is
+// applied to by default. Images, video, and other already-compressed
+// formats are deliberately left out.
+var defaultAllowedTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-yaml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+func defaultConfig() config {
+	return config{minSize: 1024, allowed: defaultAllowedTypes}
+}
+
+// WithMinSize sets the minimum response size, in bytes, before compression
+// kicks in. Smaller bodies are written through uncompressed. Defaults to
+// 1024.
+func WithMinSize(bytes int) Option {
+	return func(c *config) { c.minSize = bytes }
+}
+
+// WithAllowedTypes replaces the default Content-Type allowlist. A response
+// with a Content-Type is compressed only if it starts with one of these
+// values; a response without a Content-Type is judged by size alone.
+func WithAllowedTypes(types ...string) Option {
+	return func(c *config) { c.allowed = types }
+}
+
+// New returns compression middleware that negotiates gzip, brotli, or zstd
+// from the request's Accept-Encoding header (respecting quality values and
+// "identity;q=0"), wrapping Context.Response in a writer that also
+// implements http.Flusher (so Stream and SSE keep working) and
+// http.Hijacker (so WebSocket upgrades keep working).
+func New(opts ...Option) server.Middleware {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next server.HandlerFunc) server.HandlerFunc {
+		return func(c *server.Context) error {
+			encoding := chooseEncoding(c.Header("Accept-Encoding"))
+			if encoding == "" {
+				return next(c)
+			}
+
+			cw := newCompressWriter(c.Response, encoding, cfg.minSize, cfg.allowed)
+			c.Response = cw
+
+			err := next(c)
+			if closeErr := cw.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package compress
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// supportedEncodings lists what we can produce, in preference order used to
+// break ties when a client accepts more than one with equal quality.
+var supportedEncodings = []string{"zstd", "br", "gzip"}
+
+// chooseEncoding picks the best encoding this package supports from an
+// Accept-Encoding header, honoring quality values and "identity;q=0" /
+// "*;q=0" exclusions. Returns "" if nothing acceptable is supported (the
+// caller should serve the response uncompressed).
+func chooseEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	type pref struct {
+		token string
+		q     float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		token := part
+		q := 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			token = strings.TrimSpace(part[:i])
+			for _, param := range strings.Split(part[i+1:], ";") {
+				param = strings.TrimSpace(param)
+				if v, ok := strings.CutPrefix(param, "q="); ok {
+					if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+		}
+		prefs = append(prefs, pref{strings.ToLower(token), q})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	explicitQ := map[string]float64{}
+	wildcardQ := -1.0
+	for _, p := range prefs {
+		if p.token == "*" {
+			wildcardQ = p.q
+			continue
+		}
+		explicitQ[p.token] = p.q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supportedEncodings {
+		q, ok := explicitQ[enc]
+		if !ok {
+			q = wildcardQ
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,31 @@
+package compress
+
+import "testing"
+
+func TestChooseEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"empty header", "", ""},
+		{"single supported", "gzip", "gzip"},
+		{"prefers zstd over gzip by our own priority order", "gzip, zstd", "zstd"},
+		{"client quality values outrank our priority order", "zstd;q=0.1, gzip;q=0.9", "gzip"},
+		{"unsupported only", "compress", ""},
+		{"identity excludes nothing we support", "identity", ""},
+		{"identity;q=0 does not reject other encodings", "identity;q=0, gzip", "gzip"},
+		{"wildcard accepts our best", "*", "zstd"},
+		{"wildcard with excluded encoding", "*, zstd;q=0, br;q=0", "gzip"},
+		{"wildcard;q=0 rejects everything", "*;q=0", ""},
+		{"explicit q=0 rejects that encoding only", "zstd;q=0, br;q=0, gzip", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chooseEncoding(tt.accept); got != tt.want {
+				t.Errorf("chooseEncoding(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
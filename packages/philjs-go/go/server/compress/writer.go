@@ -0,0 +1,178 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressWriter buffers the start of a response until it can decide
+// whether to compress (by size and Content-Type), since WriteHeader may be
+// called with a status before any body bytes (or Content-Type) are known.
+// Once decided, it flushes the buffer (compressed or not) and writes
+// through to the underlying ResponseWriter for the rest of the response.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	allowed  []string
+
+	buf         bytes.Buffer
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+
+	compressor io.WriteCloser // nil if the decision was "don't compress"
+}
+
+func newCompressWriter(w http.ResponseWriter, encoding string, minSize int, allowed []string) *compressWriter {
+	return &compressWriter{
+		ResponseWriter: w,
+		encoding:       encoding,
+		minSize:        minSize,
+		allowed:        allowed,
+		statusCode:     http.StatusOK,
+	}
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.statusCode = code
+	w.wroteHeader = true
+	// Deliberately don't forward to the underlying ResponseWriter yet: the
+	// compression decision (and whether Content-Length/Content-Encoding
+	// need to change) isn't made until decide().
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.compressor != nil {
+			return w.compressor.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() >= w.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush forces the compression decision (using whatever's buffered so far)
+// so streamed writers like Context.Stream/SSE actually emit frames, then
+// flushes the underlying connection.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.compressor != nil {
+		if f, ok := w.compressor.(interface{ Flush() error }); ok {
+			_ = f.Flush()
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes through to the underlying ResponseWriter so WebSocket
+// upgrades keep working underneath this middleware.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("compress: underlying ResponseWriter does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		// The connection now belongs to the caller, so Close must not write
+		// a status line or body to it.
+		w.decided = true
+	}
+	return conn, rw, err
+}
+
+// Close finalizes the compressor, if one was used. Safe to call even if no
+// bytes were ever written (e.g. a 204 response).
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+	if w.compressor != nil {
+		return w.compressor.Close()
+	}
+	return nil
+}
+
+// decide inspects the buffered size and Content-Type to choose whether to
+// compress, then writes the (possibly rewritten) headers and buffered body.
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	contentType := w.Header().Get("Content-Type")
+	shouldCompress := w.buf.Len() >= w.minSize && isCompressible(contentType, w.allowed)
+
+	if shouldCompress {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", w.encoding)
+		addVary(w.Header())
+		w.ResponseWriter.WriteHeader(w.statusCode)
+
+		w.compressor = newCompressor(w.encoding, w.ResponseWriter)
+		_, err := w.compressor.Write(w.buf.Bytes())
+		w.buf.Reset()
+		return err
+	}
+
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func addVary(h http.Header) {
+	for _, v := range h.Values("Vary") {
+		if strings.EqualFold(v, "Accept-Encoding") {
+			return
+		}
+	}
+	h.Add("Vary", "Accept-Encoding")
+}
+
+func isCompressible(contentType string, allowed []string) bool {
+	if contentType == "" {
+		return true // nothing to judge it against; let size be the deciding factor
+	}
+	for _, prefix := range allowed {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func newCompressor(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "br":
+		return brotli.NewWriter(w)
+	case "zstd":
+		zw, _ := zstd.NewWriter(w)
+		return zw
+	default:
+		return gzip.NewWriter(w)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
-	"strings"
 )
 
 // Context holds request/response context for handlers
@@ -12,7 +11,30 @@ type Context struct {
 	Request  *http.Request
 	Response http.ResponseWriter
 	params   map[string]string
+	pattern  string
 	store    map[string]interface{}
+	span     interface{}
+	server   *Server
+}
+
+// Route returns the route pattern that was matched for this request (e.g.
+// "/users/:id"), not the raw URL path. Empty if no route matched yet.
+func (c *Context) Route() string {
+	return c.pattern
+}
+
+// Span returns the active tracing span for this request, or nil if no
+// tracing middleware (see server/observability) installed one. Callers
+// type-assert to the span type their tracing provider uses, e.g.
+// c.Span().(trace.Span).
+func (c *Context) Span() interface{} {
+	return c.span
+}
+
+// SetSpan attaches a tracing span to the context. Used by tracing
+// middleware; handlers should use Span() to read it back.
+func (c *Context) SetSpan(span interface{}) {
+	c.span = span
 }
 
 // Param returns a route parameter by name
@@ -94,10 +116,21 @@ func (c *Context) Error(code int, message string) error {
 	})
 }
 
-// Bind parses the request body into the given struct
+// Bind parses the request body into the given struct, selecting a codec by
+// the request's Content-Type (defaulting to JSON if absent or unrecognized).
 func (c *Context) Bind(v interface{}) error {
 	defer c.Request.Body.Close()
-	return json.NewDecoder(c.Request.Body).Decode(v)
+
+	codec, ok := c.codecRegistry().Get(c.Request.Header.Get("Content-Type"))
+	if !ok {
+		codec = jsonCodec{}
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, v)
 }
 
 // Body returns the raw request body
@@ -149,19 +182,6 @@ func (c *Context) InternalError(message string) error {
 	return c.Error(http.StatusInternalServerError, message)
 }
 
-// parseParams extracts route parameters from the URL path
-func (c *Context) parseParams(pattern, path string) {
-	patternParts := strings.Split(pattern, "/")
-	pathParts := strings.Split(path, "/")
-
-	for i, part := range patternParts {
-		if strings.HasPrefix(part, ":") && i < len(pathParts) {
-			paramName := strings.TrimPrefix(part, ":")
-			c.params[paramName] = pathParts[i]
-		}
-	}
-}
-
 // Stream sends a streaming response
 func (c *Context) Stream(contentType string, reader io.Reader) error {
 	c.Response.Header().Set("Content-Type", contentType)
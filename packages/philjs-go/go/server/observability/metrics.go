@@ -0,0 +1,116 @@
+package observability
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/PhilipJohnBasile/philjs/packages/philjs-go/go/server"
+)
+
+// MetricsOption configures Metrics.
+type MetricsOption func(*metricsConfig)
+
+type metricsConfig struct {
+	registerer prometheus.Registerer
+	namespace  string
+}
+
+// WithRegisterer registers metrics against reg instead of the default
+// global Prometheus registry. Useful for tests or multi-server processes.
+func WithRegisterer(reg prometheus.Registerer) MetricsOption {
+	return func(c *metricsConfig) { c.registerer = reg }
+}
+
+// WithNamespace sets the Prometheus metric namespace. Defaults to "philjs".
+func WithNamespace(namespace string) MetricsOption {
+	return func(c *metricsConfig) { c.namespace = namespace }
+}
+
+type metrics struct {
+	requestsTotal *prometheus.CounterVec
+	inFlight      *prometheus.GaugeVec
+	duration      *prometheus.HistogramVec
+}
+
+// Metrics returns middleware that records a request counter, an in-flight
+// gauge, and a request duration histogram, all labeled by method and route
+// pattern (not raw path, so cardinality stays bounded); the counter and
+// histogram are also labeled by status class.
+func Metrics(opts ...MetricsOption) server.Middleware {
+	cfg := metricsConfig{
+		registerer: prometheus.DefaultRegisterer,
+		namespace:  "philjs",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	m := &metrics{
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_requests_total",
+			Help:      "Total HTTP requests handled, labeled by route and status class.",
+		}, []string{"method", "route", "status_class"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Requests currently being handled, labeled by route.",
+		}, []string{"method", "route"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Request duration in seconds, labeled by route and status class.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status_class"}),
+	}
+	cfg.registerer.MustRegister(m.requestsTotal, m.inFlight, m.duration)
+
+	return func(next server.HandlerFunc) server.HandlerFunc {
+		return func(c *server.Context) error {
+			route := routeOrPath(c)
+			method := c.Request.Method
+
+			m.inFlight.WithLabelValues(method, route).Inc()
+			defer m.inFlight.WithLabelValues(method, route).Dec()
+
+			sw := wrapStatusWriter(c.Response)
+			c.Response = sw
+
+			start := time.Now()
+			err := next(c)
+			elapsed := time.Since(start).Seconds()
+
+			route = routeOrPath(c) // the matched route may only be known after dispatch
+			class := statusClass(sw.status)
+			m.requestsTotal.WithLabelValues(method, route, class).Inc()
+			m.duration.WithLabelValues(method, route, class).Observe(elapsed)
+
+			return err
+		}
+	}
+}
+
+// Handler returns an http.Handler serving the default Prometheus registry
+// in the exposition format, for mounting at Config.Observability.MetricsPath.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func statusClass(status int) string {
+	switch status / 100 {
+	case 2:
+		return "2xx"
+	case 3:
+		return "3xx"
+	case 4:
+		return "4xx"
+	case 5:
+		return "5xx"
+	default:
+		return strconv.Itoa(status)
+	}
+}
@@ -0,0 +1,37 @@
+package observability
+
+import (
+	"net/http"
+
+	"github.com/PhilipJohnBasile/philjs/packages/philjs-go/go/server"
+)
+
+// init registers this package as server.ObservabilityFactory, which is
+// what makes Config.Observability take effect. Import this package
+// (even as `_ "github.com/.../server/observability"`) to activate it.
+func init() {
+	server.ObservabilityFactory = build
+}
+
+func build(cfg *server.ObservabilityConfig) ([]server.Middleware, http.Handler) {
+	var middlewares []server.Middleware
+
+	if !cfg.DisableTracing {
+		middlewares = append(middlewares, Tracing(WithTracerName(serviceNameOr(cfg, "philjs-go/server"))))
+	}
+
+	var metricsHandler http.Handler
+	if !cfg.DisableMetrics {
+		middlewares = append(middlewares, Metrics(WithNamespace("philjs")))
+		metricsHandler = Handler()
+	}
+
+	return middlewares, metricsHandler
+}
+
+func serviceNameOr(cfg *server.ObservabilityConfig, fallback string) string {
+	if cfg.ServiceName != "" {
+		return cfg.ServiceName
+	}
+	return fallback
+}
@@ -0,0 +1,84 @@
+// Package observability provides OpenTelemetry tracing and Prometheus
+// metrics middleware that plug into server.Server via Server.Use.
+package observability
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/PhilipJohnBasile/philjs/packages/philjs-go/go/server"
+)
+
+// TracingOption configures Tracing.
+type TracingOption func(*tracingConfig)
+
+type tracingConfig struct {
+	tracerName string
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerName overrides the name used when acquiring the otel Tracer.
+// Defaults to "philjs-go/server".
+func WithTracerName(name string) TracingOption {
+	return func(c *tracingConfig) { c.tracerName = name }
+}
+
+// Tracing returns middleware that starts a span per request using the
+// incoming W3C traceparent header, records http.method/http.route/
+// http.status_code, and makes the span available via Context.Span().
+func Tracing(opts ...TracingOption) server.Middleware {
+	cfg := tracingConfig{
+		tracerName: "philjs-go/server",
+		propagator: propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := otel.Tracer(cfg.tracerName)
+
+	return func(next server.HandlerFunc) server.HandlerFunc {
+		return func(c *server.Context) error {
+			ctx := cfg.propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+			ctx, span := tracer.Start(ctx, c.Request.Method+" "+routeOrPath(c),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attribute.String("http.method", c.Request.Method)),
+			)
+			defer span.End()
+
+			c.Request = c.Request.WithContext(ctx)
+			c.SetSpan(span)
+
+			sw := wrapStatusWriter(c.Response)
+			c.Response = sw
+
+			err := next(c)
+
+			span.SetAttributes(
+				attribute.String("http.route", routeOrPath(c)),
+				attribute.Int("http.status_code", sw.status),
+			)
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				span.RecordError(err)
+			} else if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+			return err
+		}
+	}
+}
+
+// routeOrPath prefers the matched route pattern (bounded cardinality) over
+// the raw URL path, falling back to the path if nothing matched yet (e.g.
+// the request 404'd before a route was found).
+func routeOrPath(c *server.Context) string {
+	if route := c.Route(); route != "" {
+		return route
+	}
+	return c.Request.URL.Path
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// paramConstraint restricts what a route parameter is allowed to match, e.g.
+// ":id{int}" or ":slug{regex:[a-z0-9-]+}".
+type paramConstraint struct {
+	kind  string // "int" or "regex"
+	regex *regexp.Regexp
+}
+
+var intConstraintRegex = regexp.MustCompile(`^-?[0-9]+$`)
+
+func (c *paramConstraint) match(value string) bool {
+	if c == nil {
+		return true
+	}
+	switch c.kind {
+	case "int":
+		return intConstraintRegex.MatchString(value)
+	case "regex":
+		return c.regex.MatchString(value)
+	}
+	return true
+}
+
+// routeNode is a single segment in the routing trie. Static children are
+// keyed by their literal segment text; ":param" and "*wildcard" segments
+// each get a single dedicated child, since two different param/wildcard
+// names at the same position would make the route ambiguous.
+type routeNode struct {
+	children   map[string]*routeNode
+	paramChild *routeNode
+	paramName  string
+	constraint *paramConstraint
+
+	wildcardChild *routeNode
+	wildcardName  string
+
+	pattern  string
+	handlers map[string]HandlerFunc
+}
+
+// router is a radix/trie router keyed by path segment. It resolves each
+// request with a single walk of the trie, writing matched params directly
+// into the caller-supplied map instead of re-splitting the path.
+type router struct {
+	root *routeNode
+}
+
+func newRouter() *router {
+	return &router{root: &routeNode{}}
+}
+
+// add registers handler for method+pattern, returning an error if the
+// pattern conflicts with an existing registration (same method already
+// bound to this path, or a param/wildcard name mismatch at the same
+// position) instead of silently shadowing it.
+func (r *router) add(method, pattern string, handler HandlerFunc) error {
+	node := r.root
+	segments := splitPath(pattern)
+
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			name := strings.TrimPrefix(seg, "*")
+			if i != len(segments)-1 {
+				return fmt.Errorf("server: wildcard %q must be the last segment of %q", seg, pattern)
+			}
+			if node.wildcardChild == nil {
+				node.wildcardChild = &routeNode{}
+			} else if node.wildcardChild.wildcardName != name {
+				return fmt.Errorf("server: route conflict: %q wildcard named %q, existing route uses %q", pattern, name, node.wildcardChild.wildcardName)
+			}
+			node.wildcardChild.wildcardName = name
+			node = node.wildcardChild
+
+		case strings.HasPrefix(seg, ":"):
+			name, constraint, err := parseParamSegment(seg)
+			if err != nil {
+				return fmt.Errorf("server: %q: %w", pattern, err)
+			}
+			if node.paramChild == nil {
+				node.paramChild = &routeNode{children: make(map[string]*routeNode)}
+			} else if node.paramChild.paramName != name {
+				return fmt.Errorf("server: route conflict: %q param named %q, existing route uses %q", pattern, name, node.paramChild.paramName)
+			} else if constraint != nil && !constraintsEqual(node.paramChild.constraint, constraint) {
+				return fmt.Errorf("server: route conflict: %q constrains %q, existing route does not agree", pattern, seg)
+			}
+			node.paramChild.paramName = name
+			if constraint != nil {
+				node.paramChild.constraint = constraint
+			}
+			node = node.paramChild
+
+		default:
+			if node.children == nil {
+				node.children = make(map[string]*routeNode)
+			}
+			child, ok := node.children[seg]
+			if !ok {
+				child = &routeNode{children: make(map[string]*routeNode)}
+				node.children[seg] = child
+			}
+			node = child
+		}
+	}
+
+	if node.handlers == nil {
+		node.handlers = make(map[string]HandlerFunc)
+	}
+	if _, exists := node.handlers[method]; exists {
+		return fmt.Errorf("server: route conflict: %s %s is already registered", method, pattern)
+	}
+	node.pattern = pattern
+	node.handlers[method] = handler
+	return nil
+}
+
+// lookup resolves method+path against the trie. It returns the matched
+// handler, params, and the route pattern it was registered under on
+// success; if the path matches but method does not, handler is nil and
+// allowed lists the methods that path does accept (for a 405 response); if
+// nothing matches, handler and allowed are both nil.
+func (r *router) lookup(method, path string) (handler HandlerFunc, params map[string]string, pattern string, allowed []string) {
+	segments := splitPath(path)
+	params = make(map[string]string)
+
+	node := r.root.find(segments, 0, params)
+	if node == nil || len(node.handlers) == 0 {
+		return nil, nil, "", nil
+	}
+	if h, ok := node.handlers[method]; ok {
+		return h, params, node.pattern, nil
+	}
+
+	allowed = make([]string, 0, len(node.handlers))
+	for m := range node.handlers {
+		allowed = append(allowed, m)
+	}
+	sort.Strings(allowed)
+	return nil, nil, node.pattern, allowed
+}
+
+// find walks the trie for segments[idx:], backtracking through static,
+// then param, then wildcard children so a failed static match doesn't
+// rule out a param route at the same position. A node with no handlers
+// (an intermediate segment of some other, longer route, e.g. "b" in a
+// trie that only registered "/a/b/c") is not a match on its own — it's
+// treated the same as a dead end, so callers keep backtracking to a
+// param or wildcard sibling instead of surfacing a bare 404.
+func (n *routeNode) find(segments []string, idx int, params map[string]string) *routeNode {
+	if idx == len(segments) {
+		if len(n.handlers) > 0 {
+			return n
+		}
+		return nil
+	}
+	seg := segments[idx]
+
+	if child, ok := n.children[seg]; ok {
+		if match := child.find(segments, idx+1, params); match != nil {
+			return match
+		}
+	}
+
+	if n.paramChild != nil && n.paramChild.constraint.match(seg) {
+		prev, had := params[n.paramChild.paramName]
+		params[n.paramChild.paramName] = seg
+		if match := n.paramChild.find(segments, idx+1, params); match != nil {
+			return match
+		}
+		if had {
+			params[n.paramChild.paramName] = prev
+		} else {
+			delete(params, n.paramChild.paramName)
+		}
+	}
+
+	if n.wildcardChild != nil {
+		params[n.wildcardChild.wildcardName] = strings.Join(segments[idx:], "/")
+		return n.wildcardChild
+	}
+
+	return nil
+}
+
+// wrapAll wraps every handler registered under this node (and its
+// descendants) with mw. Used to apply Server middleware once at Start time,
+// after all routes are registered.
+func (n *routeNode) wrapAll(mw Middleware) {
+	for method, h := range n.handlers {
+		n.handlers[method] = mw(h)
+	}
+	for _, child := range n.children {
+		child.wrapAll(mw)
+	}
+	if n.paramChild != nil {
+		n.paramChild.wrapAll(mw)
+	}
+	if n.wildcardChild != nil {
+		n.wildcardChild.wrapAll(mw)
+	}
+}
+
+// constraintsEqual reports whether a and b restrict a param the same way.
+// Used to tell a genuine conflict (two routes disagreeing on the
+// constraint for the same param position) from a later registration that
+// simply doesn't repeat the constraint (e.g. "/users/:id/posts" after
+// "/users/:id{int}").
+func constraintsEqual(a, b *paramConstraint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.kind != b.kind {
+		return false
+	}
+	if a.kind == "regex" {
+		return a.regex.String() == b.regex.String()
+	}
+	return true
+}
+
+// parseParamSegment parses a ":name" or ":name{constraint}" segment, where
+// constraint is "int" or "regex:<pattern>".
+func parseParamSegment(seg string) (string, *paramConstraint, error) {
+	rest := strings.TrimPrefix(seg, ":")
+
+	open := strings.IndexByte(rest, '{')
+	if open == -1 {
+		return rest, nil, nil
+	}
+	if !strings.HasSuffix(rest, "}") {
+		return "", nil, fmt.Errorf("malformed param constraint %q", seg)
+	}
+
+	name := rest[:open]
+	raw := rest[open+1 : len(rest)-1]
+
+	switch {
+	case raw == "int":
+		return name, &paramConstraint{kind: "int"}, nil
+	case strings.HasPrefix(raw, "regex:"):
+		pattern := strings.TrimPrefix(raw, "regex:")
+		re, err := regexp.Compile("^" + pattern + "$")
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid regex constraint %q: %w", pattern, err)
+		}
+		return name, &paramConstraint{kind: "regex", regex: re}, nil
+	default:
+		return "", nil, fmt.Errorf("unknown param constraint %q", raw)
+	}
+}
+
+// splitPath splits a URL path into non-empty segments.
+func splitPath(path string) []string {
+	parts := strings.Split(path, "/")
+	segments := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			segments = append(segments, p)
+		}
+	}
+	return segments
+}
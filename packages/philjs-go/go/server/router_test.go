@@ -0,0 +1,166 @@
+package server
+
+import "testing"
+
+func noopHandler(*Context) error { return nil }
+
+func TestRouterLookup(t *testing.T) {
+	r := newRouter()
+	mustAdd(t, r, "GET", "/users", noopHandler)
+	mustAdd(t, r, "GET", "/users/:id", noopHandler)
+	mustAdd(t, r, "POST", "/users/:id", noopHandler)
+	mustAdd(t, r, "GET", "/users/:id/posts/:postID", noopHandler)
+	mustAdd(t, r, "GET", "/static/*path", noopHandler)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantMatch  bool
+		wantParams map[string]string
+		wantRoute  string
+	}{
+		{"static", "GET", "/users", true, map[string]string{}, "/users"},
+		{"param", "GET", "/users/42", true, map[string]string{"id": "42"}, "/users/:id"},
+		{"nested params", "GET", "/users/42/posts/7", true, map[string]string{"id": "42", "postID": "7"}, "/users/:id/posts/:postID"},
+		{"wildcard", "GET", "/static/css/app.css", true, map[string]string{"path": "css/app.css"}, "/static/*path"},
+		{"no match", "GET", "/missing", false, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, params, pattern, allowed := r.lookup(tt.method, tt.path)
+			if tt.wantMatch {
+				if h == nil {
+					t.Fatalf("lookup(%s, %s): got no handler, want a match", tt.method, tt.path)
+				}
+				if allowed != nil {
+					t.Fatalf("lookup(%s, %s): got allowed=%v, want nil", tt.method, tt.path, allowed)
+				}
+				if pattern != tt.wantRoute {
+					t.Errorf("pattern = %q, want %q", pattern, tt.wantRoute)
+				}
+				for k, v := range tt.wantParams {
+					if params[k] != v {
+						t.Errorf("params[%q] = %q, want %q", k, params[k], v)
+					}
+				}
+			} else if h != nil {
+				t.Fatalf("lookup(%s, %s): got a handler, want no match", tt.method, tt.path)
+			}
+		})
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	r := newRouter()
+	mustAdd(t, r, "GET", "/users/:id", noopHandler)
+	mustAdd(t, r, "PUT", "/users/:id", noopHandler)
+
+	h, _, pattern, allowed := r.lookup("DELETE", "/users/42")
+	if h != nil {
+		t.Fatalf("lookup: got a handler, want nil for unregistered method")
+	}
+	if pattern != "/users/:id" {
+		t.Errorf("pattern = %q, want /users/:id", pattern)
+	}
+	want := []string{"GET", "PUT"}
+	if len(allowed) != len(want) {
+		t.Fatalf("allowed = %v, want %v", allowed, want)
+	}
+	for i, m := range want {
+		if allowed[i] != m {
+			t.Errorf("allowed[%d] = %q, want %q", i, allowed[i], m)
+		}
+	}
+}
+
+func TestRouterParamConstraints(t *testing.T) {
+	r := newRouter()
+	mustAdd(t, r, "GET", "/users/:id{int}", noopHandler)
+
+	if h, _, _, _ := r.lookup("GET", "/users/42"); h == nil {
+		t.Fatal("lookup(/users/42): want a match against :id{int}")
+	}
+	if h, _, _, _ := r.lookup("GET", "/users/abc"); h != nil {
+		t.Fatal("lookup(/users/abc): want no match against :id{int}")
+	}
+}
+
+// A later registration at the same param position that doesn't repeat the
+// constraint must not clobber it (see the doc comment on constraintsEqual).
+func TestRouterParamConstraintPreservedAcrossRegistrations(t *testing.T) {
+	r := newRouter()
+	mustAdd(t, r, "GET", "/users/:id{int}", noopHandler)
+	mustAdd(t, r, "GET", "/users/:id/posts", noopHandler)
+
+	if h, _, _, _ := r.lookup("GET", "/users/42"); h == nil {
+		t.Fatal("lookup(/users/42): want a match against :id{int}")
+	}
+	if h, _, _, _ := r.lookup("GET", "/users/abc"); h != nil {
+		t.Fatal("lookup(/users/abc): :id{int} constraint should still apply after a later route reused the param unconstrained")
+	}
+	if h, _, _, _ := r.lookup("GET", "/users/42/posts"); h == nil {
+		t.Fatal("lookup(/users/42/posts): want a match")
+	}
+}
+
+// A handler-less intermediate node (here, "b" — only ever registered as a
+// path segment on the way to "/a/b/c", never on its own) must not shadow a
+// param sibling at the same position: find should keep backtracking past
+// it instead of surfacing a bare 404.
+func TestRouterStaticNodeWithoutHandlerFallsBackToParam(t *testing.T) {
+	r := newRouter()
+	mustAdd(t, r, "GET", "/a/b/c", noopHandler)
+	mustAdd(t, r, "GET", "/a/:x", noopHandler)
+
+	h, params, pattern, _ := r.lookup("GET", "/a/b")
+	if h == nil {
+		t.Fatal("lookup(/a/b): want a match against /a/:x, got none")
+	}
+	if pattern != "/a/:x" {
+		t.Errorf("pattern = %q, want /a/:x", pattern)
+	}
+	if params["x"] != "b" {
+		t.Errorf(`params["x"] = %q, want "b"`, params["x"])
+	}
+
+	if h, _, _, _ := r.lookup("GET", "/a/b/c"); h == nil {
+		t.Fatal("lookup(/a/b/c): want a match against the more specific route")
+	}
+}
+
+// Same shadowing bug, but for a wildcard sibling instead of a param one.
+func TestRouterStaticNodeWithoutHandlerFallsBackToWildcard(t *testing.T) {
+	r := newRouter()
+	mustAdd(t, r, "GET", "/static/css/app.css", noopHandler)
+	mustAdd(t, r, "GET", "/static/*path", noopHandler)
+
+	h, params, pattern, _ := r.lookup("GET", "/static/css")
+	if h == nil {
+		t.Fatal("lookup(/static/css): want a match against /static/*path, got none")
+	}
+	if pattern != "/static/*path" {
+		t.Errorf("pattern = %q, want /static/*path", pattern)
+	}
+	if params["path"] != "css" {
+		t.Errorf(`params["path"] = %q, want "css"`, params["path"])
+	}
+}
+
+func TestRouterParamConstraintConflict(t *testing.T) {
+	r := newRouter()
+	mustAdd(t, r, "GET", "/users/:id{int}", noopHandler)
+
+	err := r.add("GET", "/users/:id{regex:[a-z]+}", noopHandler)
+	if err == nil {
+		t.Fatal("add: want a conflict error when two routes disagree on a param's constraint")
+	}
+}
+
+func mustAdd(t *testing.T, r *router, method, pattern string, h HandlerFunc) {
+	t.Helper()
+	if err := r.add(method, pattern, h); err != nil {
+		t.Fatalf("add(%s, %s): %v", method, pattern, err)
+	}
+}
@@ -3,24 +3,38 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/http/fcgi"
 	"os"
+	"os/exec"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // Server is the main PhilJS Go server
 type Server struct {
 	mux         *http.ServeMux
-	routes      []route
+	router      *router
 	middlewares []Middleware
 	config      Config
+
+	wsConnsMu sync.Mutex
+	wsConns   map[*WebSocketConn]struct{}
+
+	codecs *CodecRegistry
 }
 
 // Config holds server configuration
@@ -36,8 +50,77 @@ type Config struct {
 	MaxBodySize int64
 	CORS        *CORSConfig
 	Edge        bool
+	// Transport selects how Start serves traffic. Defaults to TransportHTTP.
+	Transport Transport
+	// CertFile/KeyFile are used for TransportHTTPS when TLSConfig doesn't
+	// already carry certificates.
+	CertFile string
+	KeyFile  string
+	// TLSConfig, if set, is used as-is for TransportHTTPS (CertFile/KeyFile
+	// may be left empty when it already populates Certificates or
+	// GetCertificate).
+	TLSConfig *tls.Config
+	// UnixSocket is the socket path for TransportUnix and, optionally,
+	// TransportFCGI. If empty, Host is used as the path.
+	UnixSocket string
+	// GracefulRestart enables zero-downtime restarts: sending SIGHUP (or
+	// calling Server.Reload) hands the listening socket to a re-exec'd
+	// copy of the current binary before the old process drains in-flight
+	// requests and exits.
+	GracefulRestart bool
+	// Observability, when set, enables the built-in tracing and metrics
+	// middleware from server/observability without the caller needing to
+	// wire it up by hand. See ObservabilityConfig.
+	Observability *ObservabilityConfig
 }
 
+// ObservabilityConfig configures the built-in tracing and metrics
+// middleware provided by server/observability. Core does not depend on
+// observability directly (to keep it dependency-free); importing
+// server/observability registers the hook that makes this config take
+// effect.
+type ObservabilityConfig struct {
+	// ServiceName is used as the tracer name for emitted spans. Metrics are
+	// not labeled with it; they always use the "philjs" namespace.
+	ServiceName string
+	// MetricsPath is where the Prometheus handler is mounted. Defaults to
+	// "/metrics".
+	MetricsPath string
+	// DisableTracing skips installing the tracing middleware.
+	DisableTracing bool
+	// DisableMetrics skips installing the metrics middleware and endpoint.
+	DisableMetrics bool
+}
+
+// ObservabilityFactory builds the middleware (and optional metrics handler)
+// for an ObservabilityConfig. server/observability sets this in its init(),
+// so importing that package is what activates Config.Observability.
+var ObservabilityFactory func(*ObservabilityConfig) (middlewares []Middleware, metricsHandler http.Handler)
+
+// CompressFactory builds the response-compression middleware used when
+// Config.Compress is true. server/compress sets this in its init(), so
+// importing that package is what activates Config.Compress.
+var CompressFactory func() Middleware
+
+// Transport selects the protocol Server.Start serves over.
+type Transport string
+
+const (
+	// TransportHTTP serves plain HTTP/1.1 over TCP. The default.
+	TransportHTTP Transport = "http"
+	// TransportHTTPS serves HTTPS (and HTTP/2 via ALPN) over TCP, using
+	// Config.CertFile/KeyFile or Config.TLSConfig.
+	TransportHTTPS Transport = "https"
+	// TransportH2C serves cleartext HTTP/2 (no TLS), for running behind a
+	// reverse proxy that already terminates TLS.
+	TransportH2C Transport = "h2c"
+	// TransportFCGI serves over FastCGI instead of plain HTTP.
+	TransportFCGI Transport = "fcgi"
+	// TransportUnix serves plain HTTP over a Unix domain socket instead of
+	// TCP.
+	TransportUnix Transport = "unix"
+)
+
 // CORSConfig holds CORS configuration
 type CORSConfig struct {
 	Origins     []string
@@ -47,12 +130,6 @@ type CORSConfig struct {
 	MaxAge      int
 }
 
-type route struct {
-	method  string
-	pattern string
-	handler HandlerFunc
-}
-
 // HandlerFunc is the signature for route handlers
 type HandlerFunc func(*Context) error
 
@@ -62,7 +139,9 @@ type Middleware func(HandlerFunc) HandlerFunc
 // New creates a new PhilJS server with default configuration
 func New() *Server {
 	return &Server{
-		mux: http.NewServeMux(),
+		mux:    http.NewServeMux(),
+		router: newRouter(),
+		codecs: newCodecRegistry(),
 		config: Config{
 			Port:        getEnvInt("PHILJS_PORT", 3000),
 			Host:        getEnv("PHILJS_HOST", "0.0.0.0"),
@@ -90,57 +169,93 @@ func (s *Server) Use(middleware Middleware) {
 	s.middlewares = append(s.middlewares, middleware)
 }
 
-// Get registers a GET route
-func (s *Server) Get(pattern string, handler HandlerFunc) {
-	s.addRoute("GET", pattern, handler)
+// Get registers a GET route. It returns an error if pattern conflicts with
+// an existing registration.
+func (s *Server) Get(pattern string, handler HandlerFunc) error {
+	return s.addRoute("GET", pattern, handler)
 }
 
-// Post registers a POST route
-func (s *Server) Post(pattern string, handler HandlerFunc) {
-	s.addRoute("POST", pattern, handler)
+// Post registers a POST route. It returns an error if pattern conflicts with
+// an existing registration.
+func (s *Server) Post(pattern string, handler HandlerFunc) error {
+	return s.addRoute("POST", pattern, handler)
 }
 
-// Put registers a PUT route
-func (s *Server) Put(pattern string, handler HandlerFunc) {
-	s.addRoute("PUT", pattern, handler)
+// Put registers a PUT route. It returns an error if pattern conflicts with
+// an existing registration.
+func (s *Server) Put(pattern string, handler HandlerFunc) error {
+	return s.addRoute("PUT", pattern, handler)
 }
 
-// Delete registers a DELETE route
-func (s *Server) Delete(pattern string, handler HandlerFunc) {
-	s.addRoute("DELETE", pattern, handler)
+// Delete registers a DELETE route. It returns an error if pattern conflicts
+// with an existing registration.
+func (s *Server) Delete(pattern string, handler HandlerFunc) error {
+	return s.addRoute("DELETE", pattern, handler)
 }
 
-// Patch registers a PATCH route
-func (s *Server) Patch(pattern string, handler HandlerFunc) {
-	s.addRoute("PATCH", pattern, handler)
+// Patch registers a PATCH route. It returns an error if pattern conflicts
+// with an existing registration.
+func (s *Server) Patch(pattern string, handler HandlerFunc) error {
+	return s.addRoute("PATCH", pattern, handler)
 }
 
-// Options registers an OPTIONS route
-func (s *Server) Options(pattern string, handler HandlerFunc) {
-	s.addRoute("OPTIONS", pattern, handler)
+// Options registers an OPTIONS route. It returns an error if pattern
+// conflicts with an existing registration.
+func (s *Server) Options(pattern string, handler HandlerFunc) error {
+	return s.addRoute("OPTIONS", pattern, handler)
 }
 
-// Head registers a HEAD route
-func (s *Server) Head(pattern string, handler HandlerFunc) {
-	s.addRoute("HEAD", pattern, handler)
+// Head registers a HEAD route. It returns an error if pattern conflicts with
+// an existing registration.
+func (s *Server) Head(pattern string, handler HandlerFunc) error {
+	return s.addRoute("HEAD", pattern, handler)
 }
 
-func (s *Server) addRoute(method, pattern string, handler HandlerFunc) {
-	s.routes = append(s.routes, route{
-		method:  method,
-		pattern: pattern,
-		handler: handler,
-	})
+func (s *Server) addRoute(method, pattern string, handler HandlerFunc) error {
+	if s.router == nil {
+		s.router = newRouter()
+	}
+	return s.router.add(method, pattern, handler)
 }
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
-	// Setup routes
-	s.setupRoutes()
+	// Wire up built-in observability, if configured and server/observability
+	// has been imported.
+	if s.config.Observability != nil {
+		if ObservabilityFactory == nil {
+			log.Println("server: Config.Observability is set but server/observability was never imported; ignoring")
+		} else {
+			middlewares, metricsHandler := ObservabilityFactory(s.config.Observability)
+			for _, mw := range middlewares {
+				s.Use(mw)
+			}
+			if metricsHandler != nil {
+				path := s.config.Observability.MetricsPath
+				if path == "" {
+					path = "/metrics"
+				}
+				s.mux.Handle(path, metricsHandler)
+			}
+		}
+	}
 
-	// Setup static file serving
-	if s.config.StaticDir != "" {
-		s.mux.Handle("/", http.FileServer(http.Dir(s.config.StaticDir)))
+	// Wire up response compression, if enabled and server/compress has been
+	// imported. It's prepended rather than appended so it wraps (and
+	// compresses the output of) every other middleware, including
+	// observability.
+	if s.config.Compress {
+		if CompressFactory == nil {
+			log.Println("server: Config.Compress is true but server/compress was never imported; ignoring")
+		} else {
+			s.middlewares = append([]Middleware{CompressFactory()}, s.middlewares...)
+		}
+	}
+
+	// Apply middleware to every registered route once, in the same order
+	// routes would have run them if wrapped at registration time.
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		s.router.root.wrapAll(s.middlewares[i])
 	}
 
 	// Health check endpoint
@@ -149,67 +264,264 @@ func (s *Server) Start() error {
 		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	})
 
+	// Route through the trie, falling back to static files, then 404.
+	s.mux.HandleFunc("/", s.serveHTTP)
+
+	transport := s.config.Transport
+	if transport == "" {
+		transport = TransportHTTP
+	}
+
+	if transport == TransportFCGI {
+		return s.startFCGI()
+	}
+
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+
+	var handler http.Handler = s.mux
+	if transport == TransportH2C {
+		handler = h2c.NewHandler(s.mux, &http2.Server{})
+	}
+
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      s.mux,
+		Handler:      handler,
+		TLSConfig:    s.config.TLSConfig,
 		ReadTimeout:  s.config.Timeout,
 		WriteTimeout: s.config.Timeout,
 		IdleTimeout:  s.config.Timeout * 2,
 	}
 
-	// Graceful shutdown
+	network := "tcp"
+	target := addr
+	if transport == TransportUnix {
+		network = "unix"
+		target = s.config.UnixSocket
+		if target == "" {
+			target = s.config.Host
+		}
+	}
+	listener, err := s.listen(network, target)
+	if err != nil {
+		return err
+	}
+
+	// Graceful shutdown and, when enabled, zero-downtime SIGHUP restart.
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP && s.config.GracefulRestart {
+				if err := s.restart(listener); err != nil {
+					log.Printf("server: graceful restart failed, continuing to serve: %v", err)
+					continue
+				}
+				log.Println("server: handed off listener to replacement process, draining in-flight requests...")
+			} else {
+				log.Println("Shutting down server...")
+			}
+
+			s.closeWebSockets()
+
+			ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("Server shutdown error: %v", err)
+			}
+			cancel()
+			return
+		}
+	}()
+
+	log.Printf("PhilJS Go server starting (%s) on %s", transport, target)
+	if transport == TransportHTTPS {
+		err = server.ServeTLS(listener, s.config.CertFile, s.config.KeyFile)
+	} else {
+		err = server.Serve(listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// startFCGI serves s.mux over FastCGI instead of plain HTTP. FastCGI has no
+// equivalent of http.Server.Shutdown, so on SIGINT/SIGTERM this can only
+// close the listener (new connections are refused) rather than draining
+// in-flight requests the way the other transports do.
+func (s *Server) startFCGI() error {
+	network := "tcp"
+	target := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	if s.config.UnixSocket != "" || looksLikePath(s.config.Host) {
+		network = "unix"
+		target = s.config.UnixSocket
+		if target == "" {
+			target = s.config.Host
+		}
+	}
+
+	listener, err := net.Listen(network, target)
+	if err != nil {
+		return err
+	}
+
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
-		log.Println("Shutting down server...")
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		log.Println("Shutting down FastCGI server...")
+		s.closeWebSockets()
+		listener.Close()
+	}()
+
+	log.Printf("PhilJS Go server starting (fcgi) on %s", target)
+	err = fcgi.Serve(listener, s.mux)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func looksLikePath(host string) bool {
+	return strings.Contains(host, "/")
+}
+
+// Reload sends SIGHUP to the current process, triggering the same
+// zero-downtime restart as an external SIGHUP once Start is running with
+// Config.GracefulRestart true. With GracefulRestart false, Start's handler
+// treats the SIGHUP as a shutdown. Before Start has installed its signal
+// handler, the SIGHUP takes its default action, which terminates the
+// process.
+func (s *Server) Reload() error {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGHUP)
+}
 
-		if err := server.Shutdown(ctx); err != nil {
-			log.Printf("Server shutdown error: %v", err)
+// listen builds the server's net.Listener: it reconstructs the inherited
+// file descriptor from a PHILJS_LISTEN_FDS-enabled restart if present,
+// otherwise it binds addr fresh.
+func (s *Server) listen(network, target string) (net.Listener, error) {
+	if getEnvBool("PHILJS_LISTEN_FDS", false) {
+		f := os.NewFile(3, "")
+		l, err := net.FileListener(f)
+		if err != nil {
+			return nil, fmt.Errorf("server: failed to reconstruct inherited listener: %w", err)
 		}
-	}()
+		log.Println("server: resumed from inherited listener fd 3")
+		return l, nil
+	}
+	return net.Listen(network, target)
+}
 
-	log.Printf("PhilJS Go server starting on %s", addr)
-	return server.ListenAndServe()
+// restart fork+execs the current binary, handing it the listening socket
+// via ExtraFiles (landing at fd 3 in the child) so it can pick up accepting
+// connections with no gap in service.
+func (s *Server) restart(listener net.Listener) error {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	fl, ok := listener.(filer)
+	if !ok {
+		return fmt.Errorf("server: listener of type %T does not support fd passing", listener)
+	}
+	lf, err := fl.File()
+	if err != nil {
+		return fmt.Errorf("server: failed to obtain listener fd: %w", err)
+	}
+	defer lf.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("server: failed to resolve current executable: %w", err)
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "PHILJS_LISTEN_FDS=1")
+	cmd.ExtraFiles = []*os.File{lf}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("server: failed to spawn replacement process: %w", err)
+	}
+	log.Printf("server: spawned replacement process pid=%d", cmd.Process.Pid)
+	return nil
 }
 
-func (s *Server) setupRoutes() {
-	for _, r := range s.routes {
-		pattern := r.pattern
-		method := r.method
-		handler := r.handler
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.config.Timeout > 0 {
+		return s.config.Timeout
+	}
+	return 5 * time.Second
+}
 
-		// Apply middleware
-		for i := len(s.middlewares) - 1; i >= 0; i-- {
-			handler = s.middlewares[i](handler)
+// serveHTTP resolves the request against the route trie, handling the
+// match, 405 (with an Allow header listing the accepted methods), static
+// file fallback, and 404 cases.
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	handler, params, pattern, allowed := s.router.lookup(req.Method, req.URL.Path)
+
+	if handler != nil {
+		ctx := &Context{
+			Request:  req,
+			Response: w,
+			params:   params,
+			pattern:  pattern,
+			server:   s,
+		}
+		if err := handler(ctx); err != nil {
+			ctx.Error(http.StatusInternalServerError, err.Error())
 		}
+		return
+	}
 
-		s.mux.HandleFunc(pattern, func(w http.ResponseWriter, req *http.Request) {
-			// Method check
-			if req.Method != method && method != "*" {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-				return
-			}
+	if allowed != nil {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-			// Create context
-			ctx := &Context{
-				Request:  req,
-				Response: w,
-				params:   make(map[string]string),
-			}
+	if s.config.StaticDir != "" {
+		http.FileServer(http.Dir(s.config.StaticDir)).ServeHTTP(w, req)
+		return
+	}
 
-			// Parse route params
-			ctx.parseParams(pattern, req.URL.Path)
+	http.NotFound(w, req)
+}
 
-			// Execute handler
-			if err := handler(ctx); err != nil {
-				ctx.Error(http.StatusInternalServerError, err.Error())
-			}
-		})
+func (s *Server) trackWebSocket(c *WebSocketConn) {
+	s.wsConnsMu.Lock()
+	defer s.wsConnsMu.Unlock()
+	if s.wsConns == nil {
+		s.wsConns = make(map[*WebSocketConn]struct{})
+	}
+	s.wsConns[c] = struct{}{}
+}
+
+func (s *Server) untrackWebSocket(c *WebSocketConn) {
+	s.wsConnsMu.Lock()
+	defer s.wsConnsMu.Unlock()
+	delete(s.wsConns, c)
+}
+
+// closeWebSockets sends a 1001 (going away) close frame to every open
+// WebSocket connection, so clients are notified instead of simply dropped
+// when the server shuts down.
+func (s *Server) closeWebSockets() {
+	s.wsConnsMu.Lock()
+	conns := make([]*WebSocketConn, 0, len(s.wsConns))
+	for c := range s.wsConns {
+		conns = append(conns, c)
+	}
+	s.wsConnsMu.Unlock()
+
+	for _, c := range conns {
+		c.closeWithCode(1001, "server shutting down")
 	}
 }
 
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket message types, matching the RFC 6455 opcodes used on the wire.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketOptions configures a single Context.Upgrade call.
+type WebSocketOptions struct {
+	// Subprotocols are offered to the client in preference order; the first
+	// one the client also offers (via Sec-WebSocket-Protocol) is selected.
+	Subprotocols []string
+	// CheckOrigin validates the request's Origin header. If nil, a request
+	// with an Origin header is accepted only if it matches r.Host; requests
+	// without an Origin header (non-browser clients) are always accepted.
+	CheckOrigin func(r *http.Request) bool
+	// MaxMessageSize caps the size of a single (possibly reassembled)
+	// message. Defaults to 1MiB; a message exceeding it closes the
+	// connection with code 1009 (message too big).
+	MaxMessageSize int64
+	// ReadDeadline, if set, is applied to every read; it is refreshed
+	// whenever a pong is received.
+	ReadDeadline time.Duration
+	// WriteDeadline, if set, is applied to every write.
+	WriteDeadline time.Duration
+	// PingInterval, if set, sends an automatic ping on this interval to
+	// keep the connection alive and detect dead peers.
+	PingInterval time.Duration
+}
+
+func (o *WebSocketOptions) withDefaults() WebSocketOptions {
+	out := *o
+	if out.MaxMessageSize <= 0 {
+		out.MaxMessageSize = 1 << 20 // 1MiB
+	}
+	return out
+}
+
+// WebSocketConn is an upgraded WebSocket connection. Reads and writes are
+// served by per-connection goroutines over channels, so callers never touch
+// the framing layer directly.
+type WebSocketConn struct {
+	conn        net.Conn
+	rw          *bufio.ReadWriter
+	opts        WebSocketOptions
+	subprotocol string
+
+	incoming chan wsMessage
+	outgoing chan wsFrame
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+	onClose   func()
+}
+
+type wsMessage struct {
+	messageType int
+	data        []byte
+	err         error
+}
+
+type wsFrame struct {
+	opcode  byte
+	payload []byte
+	done    chan error // non-nil when the caller wants to know the write result
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake, or
+// "" if none was requested or matched.
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func newWebSocketConn(conn net.Conn, rw *bufio.ReadWriter, subprotocol string, opts WebSocketOptions) *WebSocketConn {
+	c := &WebSocketConn{
+		conn:        conn,
+		rw:          rw,
+		opts:        opts,
+		subprotocol: subprotocol,
+		incoming:    make(chan wsMessage, 16),
+		outgoing:    make(chan wsFrame, 16),
+		closed:      make(chan struct{}),
+	}
+	go c.readLoop()
+	go c.writeLoop()
+	return c
+}
+
+// ReadMessage blocks until a complete text or binary message arrives, the
+// peer closes the connection, or an error occurs.
+func (c *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	msg, ok := <-c.incoming
+	if !ok {
+		return 0, nil, c.closeErrOrEOF()
+	}
+	return msg.messageType, msg.data, msg.err
+}
+
+// WriteMessage sends a single text or binary message.
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	return c.send(wsFrame{opcode: byte(messageType), payload: data})
+}
+
+// WriteJSON marshals v and sends it as a text message.
+func (c *WebSocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// Ping sends a ping control frame.
+func (c *WebSocketConn) Ping() error {
+	return c.send(wsFrame{opcode: PingMessage})
+}
+
+// Close sends a close frame with the normal closure code and shuts down the
+// connection.
+func (c *WebSocketConn) Close() error {
+	return c.closeWithCode(1000, "")
+}
+
+func (c *WebSocketConn) closeWithCode(code int, reason string) error {
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+
+	c.finalize(wsFrame{opcode: CloseMessage, payload: payload})
+	return nil
+}
+
+// closeFrameTimeout bounds how long finalize waits for the close frame to
+// actually reach the wire before tearing down the connection anyway; a
+// wedged or half-dead peer must not hang server shutdown.
+const closeFrameTimeout = 2 * time.Second
+
+// finalize sends a close frame through writeLoop (the only goroutine
+// allowed to touch c.rw.Writer) and waits for it to be written — or for
+// closeFrameTimeout to pass — before closing the connection. It is the
+// single path both Close/closeWithCode and readLoop's peer-initiated close
+// use, guarded by closeOnce so only one close frame is ever sent.
+func (c *WebSocketConn) finalize(f wsFrame) {
+	c.closeOnce.Do(func() {
+		done := make(chan error, 1)
+		f.done = done
+		select {
+		case c.outgoing <- f:
+			select {
+			case <-done:
+			case <-time.After(closeFrameTimeout):
+			}
+		case <-time.After(closeFrameTimeout):
+		}
+
+		close(c.closed)
+		c.conn.Close()
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+}
+
+func (c *WebSocketConn) closeErrOrEOF() error {
+	if c.closeErr != nil {
+		return c.closeErr
+	}
+	return io.EOF
+}
+
+func (c *WebSocketConn) send(f wsFrame) error {
+	select {
+	case c.outgoing <- f:
+		return nil
+	case <-c.closed:
+		return net.ErrClosed
+	}
+}
+
+// Server.WebSocket registers its upgrade handler as an ordinary GET route
+// via Server.Get.
+
+// WebSocket registers a WebSocket upgrade handler at pattern. handler
+// receives the upgraded connection and owns its lifetime; the connection is
+// closed automatically once handler returns.
+func (s *Server) WebSocket(pattern string, handler func(*WebSocketConn) error) error {
+	return s.Get(pattern, func(c *Context) error {
+		conn, err := c.Upgrade(WebSocketOptions{})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return handler(conn)
+	})
+}
+
+// Upgrade switches the connection to the WebSocket protocol per RFC 6455,
+// performing the Sec-WebSocket-Key/Accept handshake, subprotocol
+// negotiation, and an origin check before hijacking the connection.
+func (c *Context) Upgrade(opts WebSocketOptions) (*WebSocketConn, error) {
+	opts = opts.withDefaults()
+	r := c.Request
+
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return nil, errors.New("server: missing or invalid Upgrade header")
+	}
+	if !headerContainsToken(r.Header.Get("Connection"), "upgrade") {
+		return nil, errors.New("server: missing or invalid Connection header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("server: unsupported Sec-WebSocket-Version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("server: missing Sec-WebSocket-Key header")
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		allowed := opts.CheckOrigin
+		if allowed == nil {
+			allowed = sameOriginCheck
+		}
+		if !allowed(r) {
+			return nil, fmt.Errorf("server: origin %q not allowed", origin)
+		}
+	}
+
+	subprotocol := negotiateSubprotocol(r.Header.Get("Sec-WebSocket-Protocol"), opts.Subprotocols)
+
+	hijacker, ok := c.Response.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("server: response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("server: hijack failed: %w", err)
+	}
+
+	if _, err := rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if subprotocol != "" {
+		if _, err := rw.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if _, err := rw.WriteString("\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	wsConn := newWebSocketConn(conn, rw, subprotocol, opts)
+	if c.server != nil {
+		c.server.trackWebSocket(wsConn)
+		wsConn.onClose = func() { c.server.untrackWebSocket(wsConn) }
+	}
+	return wsConn, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+websocketGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func negotiateSubprotocol(requested string, offered []string) string {
+	if requested == "" || len(offered) == 0 {
+		return ""
+	}
+	for _, have := range offered {
+		for _, want := range strings.Split(requested, ",") {
+			if strings.TrimSpace(want) == have {
+				return have
+			}
+		}
+	}
+	return ""
+}
+
+func sameOriginCheck(r *http.Request) bool {
+	u, err := url.Parse(r.Header.Get("Origin"))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func headerContainsToken(header, token string) bool {
+	for _, part := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// errFrameTooLarge and errUnmaskedFrame are protocol violations (as opposed
+// to a broken/reset connection): readLoop responds to them with the close
+// code RFC 6455 mandates instead of just tearing the connection down.
+var (
+	errFrameTooLarge = errors.New("server: frame exceeds max message size")
+	errUnmaskedFrame = errors.New("server: received unmasked client frame")
+)
+
+// readLoop reads frames off the connection, reassembles fragmented
+// messages, answers ping/close control frames, and publishes complete
+// messages on c.incoming. It exits (closing c.incoming) on any read error
+// or once a close frame has been processed.
+func (c *WebSocketConn) readLoop() {
+	defer close(c.incoming)
+
+	var fragType byte
+	var fragData []byte
+
+	for {
+		if c.opts.ReadDeadline > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.opts.ReadDeadline))
+		}
+
+		opcode, fin, payload, err := readFrame(c.rw.Reader, c.opts.MaxMessageSize)
+		if err != nil {
+			c.closeErr = err
+			switch {
+			case errors.Is(err, errFrameTooLarge):
+				c.closeWithCode(1009, "message too big")
+			case errors.Is(err, errUnmaskedFrame):
+				c.closeWithCode(1002, "protocol error")
+			}
+			return
+		}
+
+		switch opcode {
+		case PingMessage:
+			_ = c.send(wsFrame{opcode: PongMessage, payload: payload})
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			// Echo the close frame through writeLoop (c.rw.Writer is not
+			// safe to write from two goroutines at once), then tear down.
+			c.finalize(wsFrame{opcode: CloseMessage, payload: payload})
+			return
+		case 0: // continuation of a fragmented message
+			fragData = append(fragData, payload...)
+			if int64(len(fragData)) > c.opts.MaxMessageSize {
+				c.closeErr = fmt.Errorf("%w: message exceeds max size %d", errFrameTooLarge, c.opts.MaxMessageSize)
+				c.closeWithCode(1009, "message too big")
+				return
+			}
+		case TextMessage, BinaryMessage:
+			fragType = opcode
+			fragData = payload
+		default:
+			c.closeErr = fmt.Errorf("server: unsupported opcode %d", opcode)
+			return
+		}
+
+		if !fin {
+			continue // wait for more continuation frames
+		}
+		select {
+		case c.incoming <- wsMessage{messageType: int(fragType), data: fragData}:
+		case <-c.closed:
+			return
+		}
+		fragData = nil
+	}
+}
+
+// writeLoop serializes all outbound frames (including the keepalive ping
+// ticker) onto the connection.
+func (c *WebSocketConn) writeLoop() {
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if c.opts.PingInterval > 0 {
+		ticker = time.NewTicker(c.opts.PingInterval)
+		tick = ticker.C
+		defer ticker.Stop()
+	}
+
+	for {
+		select {
+		case f := <-c.outgoing:
+			if c.opts.WriteDeadline > 0 {
+				c.conn.SetWriteDeadline(time.Now().Add(c.opts.WriteDeadline))
+			}
+			_, err := writeFrame(c.rw.Writer, f.opcode, f.payload)
+			if f.done != nil {
+				f.done <- err
+			}
+			if err != nil {
+				return
+			}
+		case <-tick:
+			if c.opts.WriteDeadline > 0 {
+				c.conn.SetWriteDeadline(time.Now().Add(c.opts.WriteDeadline))
+			}
+			if _, err := writeFrame(c.rw.Writer, PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// readFrame reads and unmasks a single RFC 6455 frame from a client,
+// returning its opcode, FIN bit, and payload. The length is kept as a
+// uint64 until it's been range-checked against maxSize, since the 64-bit
+// extended-length form can otherwise overflow int64 into a negative number
+// and turn a bounds check into a slice-allocation panic.
+func readFrame(r io.Reader, maxSize int64) (opcode byte, fin bool, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, false, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	if !masked {
+		// RFC 6455 §5.1: a server MUST close the connection upon receiving
+		// a non-masked frame from a client.
+		return 0, false, nil, errUnmaskedFrame
+	}
+	length := uint64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, false, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, false, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > uint64(maxSize) {
+		return 0, false, nil, fmt.Errorf("%w: %d bytes exceeds max message size %d", errFrameTooLarge, length, maxSize)
+	}
+
+	var maskKey [4]byte
+	if _, err := io.ReadFull(r, maskKey[:]); err != nil {
+		return 0, false, nil, err
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, false, nil, err
+	}
+	for i := range data {
+		data[i] ^= maskKey[i%4]
+	}
+
+	return opcode, fin, data, nil
+}
+
+// writeFrame writes a single, final (FIN-set), unmasked frame — server to
+// client frames are never masked per RFC 6455.
+func writeFrame(w io.Writer, opcode byte, payload []byte) (int, error) {
+	length := len(payload)
+	header := make([]byte, 0, 10)
+	header = append(header, 0x80|opcode) // FIN + opcode
+
+	switch {
+	case length <= 125:
+		header = append(header, byte(length))
+	case length <= 0xffff:
+		ext := make([]byte, 2)
+		binary.BigEndian.PutUint16(ext, uint16(length))
+		header = append(header, 126)
+		header = append(header, ext...)
+	default:
+		ext := make([]byte, 8)
+		binary.BigEndian.PutUint64(ext, uint64(length))
+		header = append(header, 127)
+		header = append(header, ext...)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return 0, err
+	}
+	if len(payload) > 0 {
+		if _, err := w.Write(payload); err != nil {
+			return 0, err
+		}
+	}
+	if f, ok := w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(payload), nil
+}
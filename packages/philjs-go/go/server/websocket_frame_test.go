@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// maskedFrame builds a client->server RFC 6455 frame (always masked) with
+// the given opcode, fin bit, and payload.
+func maskedFrame(opcode byte, fin bool, payload []byte) []byte {
+	var header byte = opcode
+	if fin {
+		header |= 0x80
+	}
+
+	buf := []byte{header}
+	length := len(payload)
+	switch {
+	case length <= 125:
+		buf = append(buf, 0x80|byte(length))
+	case length <= 0xffff:
+		buf = append(buf, 0x80|126, byte(length>>8), byte(length))
+	default:
+		buf = append(buf, 0x80|127,
+			byte(length>>56), byte(length>>48), byte(length>>40), byte(length>>32),
+			byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+	}
+
+	maskKey := [4]byte{0x12, 0x34, 0x56, 0x78}
+	buf = append(buf, maskKey[:]...)
+	masked := make([]byte, length)
+	for i, b := range payload {
+		masked[i] = b ^ maskKey[i%4]
+	}
+	return append(buf, masked...)
+}
+
+func TestReadFrameUnmasksPayload(t *testing.T) {
+	want := []byte("hello")
+	frame := maskedFrame(TextMessage, true, want)
+
+	opcode, fin, payload, err := readFrame(bytes.NewReader(frame), 1<<20)
+	if err != nil {
+		t.Fatalf("readFrame: %v", err)
+	}
+	if opcode != TextMessage {
+		t.Errorf("opcode = %d, want %d", opcode, TextMessage)
+	}
+	if !fin {
+		t.Error("fin = false, want true")
+	}
+	if !bytes.Equal(payload, want) {
+		t.Errorf("payload = %q, want %q", payload, want)
+	}
+}
+
+func TestReadFrameRejectsOversizedFrame(t *testing.T) {
+	frame := maskedFrame(BinaryMessage, true, make([]byte, 100))
+
+	if _, _, _, err := readFrame(bytes.NewReader(frame), 10); err == nil {
+		t.Fatal("readFrame: want an error for a frame exceeding maxSize")
+	}
+}
+
+// TestReadFrameRejectsHighBitLengthWithoutPanicking guards against a
+// crafted 64-bit extended length with the high bit set (e.g.
+// 0x8000000000000000): interpreted as int64 that's negative, so a naive
+// `length > maxSize` check passes and `make([]byte, length)` panics. It
+// must be rejected as too large instead.
+func TestReadFrameRejectsHighBitLengthWithoutPanicking(t *testing.T) {
+	frame := []byte{
+		0x80 | BinaryMessage, // FIN + opcode
+		0x80 | 127,           // masked + 64-bit extended length marker
+		0x80, 0, 0, 0, 0, 0, 0, 0, // length with the high bit set
+	}
+
+	_, _, _, err := readFrame(bytes.NewReader(frame), 1<<20)
+	if err == nil {
+		t.Fatal("readFrame: want an error, not a match, for a frame with the length high bit set")
+	}
+	if !errors.Is(err, errFrameTooLarge) {
+		t.Errorf("err = %v, want errFrameTooLarge", err)
+	}
+}
+
+func TestReadFrameRejectsUnmaskedFrame(t *testing.T) {
+	frame := []byte{
+		0x80 | TextMessage, // FIN + opcode
+		3,                  // mask bit clear, length 3
+		'h', 'i', '!',
+	}
+
+	_, _, _, err := readFrame(bytes.NewReader(frame), 1<<20)
+	if !errors.Is(err, errUnmaskedFrame) {
+		t.Errorf("err = %v, want errUnmaskedFrame", err)
+	}
+}
+
+// writeFrame produces server-to-client frames, which RFC 6455 requires to
+// be unmasked — so this checks its output directly rather than round
+// tripping through readFrame, which (correctly) rejects unmasked frames.
+func TestWriteFrameWritesUnmaskedFrame(t *testing.T) {
+	var buf bytes.Buffer
+	payload := []byte("round trip")
+	if _, err := writeFrame(&buf, TextMessage, payload); err != nil {
+		t.Fatalf("writeFrame: %v", err)
+	}
+
+	got := buf.Bytes()
+	wantHeader := []byte{0x80 | TextMessage, byte(len(payload))}
+	if !bytes.Equal(got[:2], wantHeader) {
+		t.Fatalf("header = % x, want % x", got[:2], wantHeader)
+	}
+	if !bytes.Equal(got[2:], payload) {
+		t.Fatalf("payload = %q, want %q", got[2:], payload)
+	}
+}
+
+// pipeConn wires up a WebSocketConn over a net.Pipe so readLoop/writeLoop
+// run against a real connection, and returns the peer end for the test to
+// drive directly with raw frame bytes.
+func pipeConn(t *testing.T, opts WebSocketOptions) (*WebSocketConn, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	rw := bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))
+	wsConn := newWebSocketConn(server, rw, "", opts.withDefaults())
+	t.Cleanup(func() { wsConn.Close() })
+	return wsConn, client
+}
+
+func TestReadLoopReassemblesFragmentedMessage(t *testing.T) {
+	wsConn, client := pipeConn(t, WebSocketOptions{})
+	defer client.Close()
+
+	go func() {
+		client.Write(maskedFrame(TextMessage, false, []byte("hel")))
+		client.Write(maskedFrame(0, false, []byte("lo ")))
+		client.Write(maskedFrame(0, true, []byte("world")))
+	}()
+
+	msgType, data, err := wsConn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if msgType != TextMessage {
+		t.Errorf("messageType = %d, want %d", msgType, TextMessage)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("data = %q, want %q", data, "hello world")
+	}
+}
+
+func TestReadLoopClosesOnOversizedMessage(t *testing.T) {
+	wsConn, client := pipeConn(t, WebSocketOptions{MaxMessageSize: 4})
+	defer client.Close()
+
+	// readLoop also tries to send a 1009 close frame back once it detects
+	// the oversized message; drain it so that write doesn't block for the
+	// full closeFrameTimeout and slow the test down.
+	go io.Copy(io.Discard, client)
+	go client.Write(maskedFrame(TextMessage, true, []byte("too long")))
+
+	select {
+	case _, ok := <-wsConn.incoming:
+		if ok {
+			t.Fatal("incoming: want channel closed after oversized message, got a message instead")
+		}
+	case <-time.After(closeFrameTimeout + time.Second):
+		t.Fatal("timed out waiting for readLoop to close incoming")
+	}
+}